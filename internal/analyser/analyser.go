@@ -0,0 +1,40 @@
+package analyser
+
+import "context"
+
+// Analyser analyses a repository and the patch found at diffURL for issues,
+// returning any issues found restricted to the lines changed by the patch.
+// Implementations must return promptly once ctx is cancelled or its deadline
+// is exceeded, cleaning up any resources (temp directories, containers) they
+// created.
+type Analyser interface {
+	Analyse(ctx context.Context, repoURL, branch, diffURL string) ([]Issue, error)
+}
+
+// Issue is a single issue found by a static analysis tool. Analyse returns
+// only Issues on a line changed within the patch, with HunkPos set.
+type Issue struct {
+	// File is the filename the issue was found in, relative to the
+	// repository root.
+	File string
+	// Line is the absolute line number the tool reported the issue against,
+	// before it was filtered and translated to HunkPos. Zero if the tool's
+	// output format didn't report a line.
+	Line int
+	// HunkPos is the line number within the diff hunk the issue applies to.
+	HunkPos int
+	// Column is the 1-based column the issue applies to, zero if the tool's
+	// output format doesn't report one.
+	Column int
+	// Severity is the tool-reported severity, e.g. "error" or "warning",
+	// empty if the tool's output format doesn't report one.
+	Severity string
+	// Rule identifies the specific check that produced the issue, e.g.
+	// "govet:printf", empty if the tool's output format doesn't report one.
+	Rule string
+	// Issue is the message reported by the static analysis tool.
+	Issue string
+	// SuggestedFix is a tool-provided replacement for the reported line,
+	// empty if the tool didn't suggest one.
+	SuggestedFix string
+}
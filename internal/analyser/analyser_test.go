@@ -1,136 +1,115 @@
-	ExecuteErr []error
-	err, a.ExecuteErr = a.ExecuteErr[0], a.ExecuteErr[1:]
-func TestAnalyse_pr(t *testing.T) {
-	cfg := Config{
-		EventType: EventTypePullRequest,
-		BaseURL:   "base-url",
-		BaseRef:   "base-branch",
-		HeadURL:   "head-url",
-		HeadRef:   "head-branch",
+package analyser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeVCS records each Clone call and returns a canned error, mirroring
+// mockContainerExecutor's approach in container_test.go.
+type fakeVCS struct {
+	cloneErr error
+	cloned   []string // repoURL, ref, dir
+}
+
+func (f *fakeVCS) Clone(ctx context.Context, url, ref, dir string) error {
+	f.cloned = []string{url, ref, dir}
+	return f.cloneErr
+}
+
+func TestFileSystem_Analyse_cloneError(t *testing.T) {
+	diffServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("diff --git a/main.go b/main.go\n"))
+	}))
+	defer diffServer.Close()
+
+	vcs := &fakeVCS{cloneErr: errors.New("clone failed")}
+
+	root := t.TempDir()
+	fs, err := NewFileSystem(root, 1, Config{}, vcs, ModulesConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	tools := []db.Tool{
-		{Name: "Name1", Path: "tool1", Args: "-flag %BASE_BRANCH% ./..."},
-		{Name: "Name2", Path: "tool2"},
-		{Name: "Name2", Path: "tool3"},
+	if _, err := fs.Analyse(context.Background(), "repo-url", "branch", diffServer.URL); err == nil {
+		t.Fatal("expected error from a failed clone, got nil")
 	}
 
-	diff := []byte(`diff --git a/subdir/main.go b/subdir/main.go
-	analyser := &mockAnalyser{
-		ExecuteOut: [][]byte{
-			{},   // git clone
-			{},   // git fetch
-			diff, // git diff
-			{},   // install-deps.sh
-			[]byte(`/go/src/gopherci`),                   // pwd
-			[]byte("main.go:1: error1"),                  // tool 1
-			[]byte("file is not generated"),              // isFileGenerated
-			[]byte("/go/src/gopherci/main.go:1: error2"), // tool 2 output abs paths
-			[]byte("file is not generated"),              // isFileGenerated
-			[]byte("main.go:1: error3"),                  // tool 3 tested a generated file
-			[]byte("file is generated"),                  // isFileGenerated
-		},
-		ExecuteErr: []error{
-			nil, // git clone
-			nil, // git fetch
-			nil, // git diff
-			nil, // install-deps.sh
-			nil, // pwd
-			nil, // tool 1
-			&NonZeroError{ExitCode: 1}, // isFileGenerated - not generated
-			nil, // tool 2 output abs paths
-			&NonZeroError{ExitCode: 1}, // isFileGenerated - not generated
-			nil, // tool 3 tested a generated file
-			nil, // isFileGenerated - generated
-		},
+	if want := []string{"repo-url", "branch"}; vcs.cloned[0] != want[0] || vcs.cloned[1] != want[1] {
+		t.Errorf("clone called with repoURL %q ref %q, want %q %q", vcs.cloned[0], vcs.cloned[1], want[0], want[1])
 	}
+}
 
-	issues, err := Analyse(analyser, tools, cfg)
+func TestFileSystem_Analyse_diffDownloadError(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewFileSystem(root, 1, Config{}, &fakeVCS{}, ModulesConfig{}, nil)
 	if err != nil {
-		t.Fatal("unexpected error:", err)
+		t.Fatal(err)
 	}
 
-	expected := []Issue{
-		{File: "main.go", HunkPos: 1, Issue: "Name1: error1"},
-		{File: "main.go", HunkPos: 1, Issue: "Name2: error2"},
+	if _, err := fs.Analyse(context.Background(), "repo-url", "branch", "://not-a-url"); err == nil {
+		t.Fatal("expected error from an invalid diffURL, got nil")
 	}
-	if !reflect.DeepEqual(expected, issues) {
-		t.Errorf("expected issues:\n%+v\ngot:\n%+v", expected, issues)
+}
+
+func TestFileSystem_resolveVCS(t *testing.T) {
+	root := t.TempDir()
+	fallback := GitVCS{}
+	fs, err := NewFileSystem(root, 1, Config{}, fallback, ModulesConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if !analyser.Stopped {
-		t.Errorf("expected analyser to be stopped")
+	override := &fakeVCS{}
+	fs.SetVCSOverride("repo-url", override)
+
+	if got := fs.resolveVCS("repo-url"); got != VCS(override) {
+		t.Errorf("resolveVCS(repo-url) = %#v, want the override %#v", got, override)
+	}
+
+	if got := fs.resolveVCS("other-url"); got != VCS(fallback) {
+		t.Errorf("resolveVCS(other-url) = %#v, want the fallback %#v", got, fallback)
+	}
+}
+
+func TestNewFileSystem_overrides(t *testing.T) {
+	root := t.TempDir()
+	fallback := GitVCS{}
+	override := &fakeVCS{}
+
+	// overrides mimics per-repo preferences read from the database at
+	// startup and passed to NewFileSystem, rather than applied one at a
+	// time via SetVCSOverride.
+	fs, err := NewFileSystem(root, 1, Config{}, fallback, ModulesConfig{}, map[string]VCS{"repo-url": override})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	expectedArgs := [][]string{
-		{"git", "clone", "--depth", "1", "--branch", cfg.HeadRef, "--single-branch", cfg.HeadURL, "."},
-		{"git", "fetch", "--depth", "1", cfg.BaseURL, cfg.BaseRef},
-		{"git", "diff", fmt.Sprintf("FETCH_HEAD...%v", cfg.HeadRef)},
-		{"install-deps.sh"},
-		{"pwd"},
-		{"tool1", "-flag", "FETCH_HEAD", "./..."},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
-		{"tool2"},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
-		{"tool3"},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
+	if got := fs.resolveVCS("repo-url"); got != VCS(override) {
+		t.Errorf("resolveVCS(repo-url) = %#v, want the seeded override %#v", got, override)
 	}
 
-	if !reflect.DeepEqual(analyser.Executed, expectedArgs) {
-		t.Errorf("\nhave %v\nwant %v", analyser.Executed, expectedArgs)
+	if got := fs.resolveVCS("other-url"); got != VCS(fallback) {
+		t.Errorf("resolveVCS(other-url) = %#v, want the fallback %#v", got, fallback)
 	}
 }
 
-func TestAnalyse_push(t *testing.T) {
-		EventType: EventTypePush,
-		BaseURL:   "base-url",
-		BaseRef:   "abcde~1",
-		HeadURL:   "head-url",
-		HeadRef:   "abcde",
-		{Name: "Name2", Path: "tool3"},
-	diff := []byte(`diff --git a/subdir/main.go b/subdir/main.go
-new file mode 100644
-index 0000000..6362395
---- /dev/null
-+++ b/main.go
-@@ -0,0 +1,1 @@
-+var _ = fmt.Sprintln()`)
-
-			{},   // git clone
-			{},   // git checkout
-			diff, // git diff
-			{},   // install-deps.sh
-			[]byte("file is not generated"),              // isFileGenerated
-			[]byte("file is not generated"),              // isFileGenerated
-			[]byte("main.go:1: error3"),                  // tool 3 tested a generated file
-			[]byte("file is generated"),                  // isFileGenerated
-		},
-		ExecuteErr: []error{
-			nil, // git clone
-			nil, // git checkout
-			nil, // git diff
-			nil, // install-deps.sh
-			nil, // pwd
-			nil, // tool 1
-			&NonZeroError{ExitCode: 1}, // isFileGenerated - not generated
-			nil, // tool 2 output abs paths
-			&NonZeroError{ExitCode: 1}, // isFileGenerated - not generated
-			nil, // tool 3 tested a generated file
-			nil, // isFileGenerated - generated
-		{"git", "clone", cfg.HeadURL, "."},
-		{"git", "checkout", cfg.HeadRef},
-		{"git", "diff", fmt.Sprintf("%v...%v", cfg.BaseRef, cfg.HeadRef)},
-		{"tool1", "-flag", "abcde~1", "./..."},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
-		{"tool3"},
-		{"isFileGenerated", "/go/src/gopherci", "main.go"},
-
-func TestAnalyse_unknown(t *testing.T) {
-	cfg := Config{}
-	analyser := &mockAnalyser{}
-	_, err := Analyse(analyser, nil, cfg)
-	if err == nil {
-		t.Fatal("expected error got nil")
+func TestWithPhaseTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	childCtx, cancel := withPhaseTimeout(ctx, 0)
+	defer cancel()
+	if _, ok := childCtx.Deadline(); ok {
+		t.Error("expected no deadline when d is zero")
 	}
-}
\ No newline at end of file
+
+	childCtx, cancel = withPhaseTimeout(ctx, time.Second)
+	defer cancel()
+	if _, ok := childCtx.Deadline(); !ok {
+		t.Error("expected a deadline when d is non-zero")
+	}
+}
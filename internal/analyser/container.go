@@ -0,0 +1,218 @@
+package analyser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultImage is used to run a tool when it hasn't been configured with
+// its own Image.
+const defaultImage = "golang"
+
+// Tool is a single static analysis tool to be run against a repository.
+// Unlike FileSystem, each Tool is run within its own ephemeral container.
+type Tool struct {
+	// Name is a human readable name shown alongside any issues found.
+	Name string
+	// Path is the executable to run, found on the container's PATH.
+	Path string
+	// Args are passed to Path, space separated.
+	Args string
+	// Image is the container image Path is run within, defaults to
+	// defaultImage if empty.
+	Image string
+	// Format identifies the structure of Path's output, defaults to
+	// FormatText if empty.
+	Format ToolFormat
+}
+
+// containerExecutor runs name and args inside an ephemeral container and
+// returns its stdout and stderr separately, so structured tool output
+// (SARIF, checkstyle, etc) on stdout isn't corrupted by unrelated stderr
+// noise, e.g. build or module download logs. workspace is bind-mounted
+// into the container at /workspace, read-only if readOnly is true, and the
+// container has no network access unless network is true.
+type containerExecutor interface {
+	Run(ctx context.Context, image, workspace string, readOnly, network bool, name string, args ...string) (stdout, stderr []byte, err error)
+}
+
+// Container analyses a repository and patch for issues by running git,
+// dependency fetching and each configured tool inside an ephemeral
+// container, rather than directly on the host as FileSystem does. This
+// allows GopherCI to safely analyse pull requests from untrusted forks, as
+// arbitrary code executed via go generate, cgo, install-deps.sh or a
+// malicious tool cannot escape the container.
+//
+// Container is safe to use concurrently, as each Analyse call uses its own
+// temporary workspace and containers.
+type Container struct {
+	// executor starts and runs each container, overridable in tests.
+	executor containerExecutor
+
+	// tools to run against the repository, each within its own container.
+	tools []Tool
+
+	// cfg holds the per-phase deadlines applied during Analyse.
+	cfg Config
+}
+
+// Ensure Container implements Analyser
+var _ Analyser = (*Container)(nil)
+
+// NewContainer returns a Container that clones repoURL and fetches
+// dependencies with network access, then runs each of tools inside its own
+// container with the resulting workspace bind-mounted read-only and without
+// network access.
+func NewContainer(tools []Tool, cfg Config) (*Container, error) {
+	return &Container{
+		executor: dockerExecutor{},
+		tools:    tools,
+		cfg:      cfg,
+	}, nil
+}
+
+// Analyse implements Analyser interface
+func (c *Container) Analyse(ctx context.Context, repoURL, branch, diffURL string) ([]Issue, error) {
+	log.Printf("container.Analyse repoURL %q branch %q diffURL %q", repoURL, branch, diffURL)
+
+	if c.cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.TotalTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, diffURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer patch.Body.Close()
+
+	workspace, err := ioutil.TempDir("", "gopherci-container-")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create workspace")
+	}
+	defer os.RemoveAll(workspace)
+
+	// Clone and fetch dependencies with network access, the resulting
+	// workspace is then handed to each tool's container read-only and
+	// without network access.
+	cloneCtx, cancel := withPhaseTimeout(ctx, c.cfg.CloneTimeout)
+	defer cancel()
+
+	if _, _, err := c.executor.Run(cloneCtx, defaultImage, workspace, false, true,
+		"git", "clone", "--branch", branch, "--depth", "1", "--single-branch", repoURL, "."); err != nil {
+		return nil, errors.Wrap(err, "could not clone repository")
+	}
+
+	if _, _, err := c.executor.Run(cloneCtx, defaultImage, workspace, false, true, "./install-deps.sh"); err != nil {
+		// Not all repositories have an install-deps.sh, so don't treat
+		// this as fatal.
+		log.Println("install-deps.sh failed, continuing:", err)
+	}
+
+	var allIssues []Issue
+	for _, tool := range c.tools {
+		image := tool.Image
+		if image == "" {
+			image = defaultImage
+		}
+
+		toolCtx, cancel := withPhaseTimeout(ctx, c.cfg.ToolTimeout)
+		stdout, stderr, err := c.executor.Run(toolCtx, image, workspace, true, false, tool.Path, splitArgs(tool.Args)...)
+		cancel()
+		if err != nil {
+			// Many tools exit non-zero simply because they found issues,
+			// so don't treat this as fatal, mirroring FileSystem.
+			log.Printf("tool %v failed, continuing: %v", tool.Name, err)
+		}
+
+		// Structured formats (SARIF, checkstyle, etc) are only valid on
+		// stdout: stderr noise like build or module download logs would
+		// otherwise corrupt the XML/JSON. FormatText tools, e.g. go vet,
+		// traditionally report through stderr, so give those both streams.
+		out := stdout
+		if tool.Format == FormatText || tool.Format == "" {
+			out = append(append([]byte{}, stdout...), stderr...)
+		}
+
+		issues, err := parseToolOutput(tool.Format, tool.Name, out)
+		if err != nil {
+			log.Printf("tool %v: could not parse output, continuing: %v", tool.Name, err)
+			continue
+		}
+		allIssues = append(allIssues, issues...)
+	}
+
+	issues, err := filterIssuesByPatch(allIssues, patch.Body)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("found %v issues within the patch", len(issues))
+
+	return issues, nil
+}
+
+// splitArgs splits a space separated argument string, returning nil if args
+// is empty.
+func splitArgs(args string) []string {
+	if args == "" {
+		return nil
+	}
+	return strings.Fields(args)
+}
+
+// dockerExecutor runs commands using the docker CLI, each invocation starts
+// and removes its own container.
+type dockerExecutor struct{}
+
+var _ containerExecutor = dockerExecutor{}
+
+// Run implements containerExecutor. CPU, memory and pids are limited to
+// conservative defaults suitable for a single static analysis tool
+// invocation. When ctx is cancelled or its deadline is exceeded, docker is
+// sent SIGKILL and the (already --rm'd) container is removed by the daemon.
+func (dockerExecutor) Run(ctx context.Context, image, workspace string, readOnly, network bool, name string, args ...string) ([]byte, []byte, error) {
+	dockerArgs := []string{
+		"run", "--rm",
+		"--cpus", "1",
+		"--memory", "512m",
+		"--pids-limit", "128",
+		"--workdir", "/workspace",
+	}
+
+	if !network {
+		dockerArgs = append(dockerArgs, "--network", "none")
+	}
+
+	mount := workspace + ":/workspace"
+	if readOnly {
+		mount += ":ro"
+	}
+	dockerArgs = append(dockerArgs, "-v", mount, image, name)
+	dockerArgs = append(dockerArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "docker", dockerArgs...)
+	log.Printf("path: %v %v", cmd.Path, cmd.Args)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), stderr.Bytes(), fmt.Errorf("could not %v %v: %s\n%s", cmd.Path, cmd.Args, err, stderr.Bytes())
+	}
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
@@ -0,0 +1,198 @@
+package analyser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockContainerExecutor records each Run call and returns canned output.
+type mockContainerExecutor struct {
+	out [][]byte
+	err []error
+
+	readOnly  []bool
+	network   []bool
+	calls     [][]string
+	workspace []string
+
+	// block, if non-nil, is waited on (alongside ctx.Done) by calls at index
+	// blockFrom onwards, used to test that ctx cancellation is respected.
+	block     <-chan struct{}
+	blockFrom int
+
+	// started, if non-nil, is closed the first time a call begins blocking,
+	// letting a test synchronize cancelling ctx with the call actually being
+	// in flight.
+	started     chan struct{}
+	startedOnce sync.Once
+}
+
+func (m *mockContainerExecutor) Run(ctx context.Context, image, workspace string, readOnly, network bool, name string, args ...string) ([]byte, []byte, error) {
+	m.readOnly = append(m.readOnly, readOnly)
+	m.network = append(m.network, network)
+	m.calls = append(m.calls, append([]string{image, name}, args...))
+	m.workspace = append(m.workspace, workspace)
+
+	if m.block != nil && len(m.calls)-1 >= m.blockFrom {
+		if m.started != nil {
+			m.startedOnce.Do(func() { close(m.started) })
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-m.block:
+		}
+	}
+
+	out, err := m.out[0], m.err[0]
+	m.out, m.err = m.out[1:], m.err[1:]
+	return out, nil, err
+}
+
+func TestContainer_Analyse(t *testing.T) {
+	tools := []Tool{
+		{Name: "vet", Path: "go", Args: "vet ./..."},
+		{Name: "custom", Path: "customtool", Image: "customimage"},
+	}
+
+	executor := &mockContainerExecutor{
+		out: [][]byte{{}, {}, []byte("main.go:1: error1"), []byte("main.go:1: error2")},
+		err: []error{nil, nil, nil, nil},
+	}
+
+	diffServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`diff --git a/main.go b/main.go
+index 0000000..6362395 100644
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+-var _ = fmt.Sprintln()
++var _ = fmt.Sprintln() // changed`))
+	}))
+	defer diffServer.Close()
+
+	c := &Container{executor: executor, tools: tools}
+
+	if _, err := c.Analyse(context.Background(), "repo-url", "branch", diffServer.URL); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := [][]string{
+		{defaultImage, "git", "clone", "--branch", "branch", "--depth", "1", "--single-branch", "repo-url", "."},
+		{defaultImage, "./install-deps.sh"},
+		{defaultImage, "go", "vet", "./..."},
+		{"customimage", "customtool"},
+	}
+
+	if !reflect.DeepEqual(expected, executor.calls) {
+		t.Errorf("\nhave %v\nwant %v", executor.calls, expected)
+	}
+
+	if executor.readOnly[0] || executor.readOnly[1] {
+		t.Error("clone and install-deps.sh should run against a writable workspace")
+	}
+	if !executor.readOnly[2] || !executor.readOnly[3] {
+		t.Error("tools should run against a read-only workspace")
+	}
+	if !executor.network[0] || !executor.network[1] {
+		t.Error("clone and install-deps.sh should have network access")
+	}
+	if executor.network[2] || executor.network[3] {
+		t.Error("tools should not have network access")
+	}
+}
+
+func TestContainer_Analyse_cancel(t *testing.T) {
+	diffServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("diff --git a/main.go b/main.go\n"))
+	}))
+	defer diffServer.Close()
+
+	executor := &mockContainerExecutor{
+		block:   make(chan struct{}),
+		started: make(chan struct{}),
+	}
+
+	c := &Container{executor: executor, tools: nil}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Analyse(ctx, "repo-url", "branch", diffServer.URL)
+		errc <- err
+	}()
+
+	// Wait for the clone to actually be in flight (blocked in the executor)
+	// before cancelling, so this exercises ctx cancellation mid-run rather
+	// than a context that was already done before Analyse started.
+	<-executor.started
+	cancel()
+
+	err := <-errc
+	if err == nil {
+		t.Fatal("expected error from cancelled context, got nil")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to wrap context.Canceled, got %v", err)
+	}
+
+	if len(executor.workspace) == 0 {
+		t.Fatal("expected the executor to have been called with a workspace")
+	}
+	if _, err := os.Stat(executor.workspace[0]); !os.IsNotExist(err) {
+		t.Errorf("expected workspace %v to be removed once Analyse returned, got err=%v", executor.workspace[0], err)
+	}
+}
+
+func TestContainer_Analyse_toolTimeout(t *testing.T) {
+	diffServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("diff --git a/main.go b/main.go\n"))
+	}))
+	defer diffServer.Close()
+
+	executor := &mockContainerExecutor{
+		out:       [][]byte{{}, {}},
+		err:       []error{nil, nil},
+		block:     make(chan struct{}),
+		blockFrom: 2,
+	}
+
+	c := &Container{
+		executor: executor,
+		tools:    []Tool{{Name: "slow", Path: "slowtool"}},
+		cfg:      Config{ToolTimeout: 10 * time.Millisecond},
+	}
+
+	if _, err := c.Analyse(context.Background(), "repo-url", "branch", diffServer.URL); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(executor.calls) != 3 {
+		t.Fatalf("expected clone, install-deps.sh and the timed-out tool to run, got %v calls", len(executor.calls))
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"", nil},
+		{"./...", []string{"./..."}},
+		{"-flag base-branch ./...", []string{"-flag", "base-branch", "./..."}},
+	}
+
+	for _, tt := range tests {
+		if got := splitArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
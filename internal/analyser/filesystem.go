@@ -2,85 +2,171 @@ package analyser
 
 import (
 	"bytes"
-	"fmt"
-	"io/ioutil"
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"time"
 
-	"github.com/bradleyfalzon/revgrep"
 	"github.com/pkg/errors"
 )
 
+// Config holds per-phase deadlines applied on top of the ctx passed to
+// Analyse. A zero value means no additional deadline is applied for that
+// phase, it is instead bound only by ctx and, transitively, TotalTimeout.
+type Config struct {
+	// CloneTimeout bounds how long cloning the repository may take.
+	CloneTimeout time.Duration
+	// ToolTimeout bounds how long a single static analysis tool may take.
+	ToolTimeout time.Duration
+	// TotalTimeout bounds the entire Analyse call.
+	TotalTimeout time.Duration
+}
+
 // FileSystem analyses a repository and patch for issues using the file
 // system. This is an insecure option and therefore should only be used when
 // analysing a known safe repository with known safe static analysis tools.
 //
-// FileSystem is safe to use concurrently, as all directories are created
-// with random file names.
+// FileSystem is safe to use concurrently: each Analyse call leases its own
+// workspace from pool for the duration of the call.
 type FileSystem struct {
-	// gopath specifies the GOPATH to be set in the environment. Respositories
-	// to be checked will be downloaded to $GOPATH/src/gopherci/, if the
-	// repository directory already exists, it will be deleted.
-	gopath string
-
-	// copath specifies the base checkout path used, a temp folder name is created
-	// within here to avoid race conditions with other threads.
-	copath string
+	// pool hands out the GOPATH workspaces repositories are checked out
+	// into, bounded to a fixed number leased concurrently.
+	pool *workspacePool
+
+	// cfg holds the per-phase deadlines applied during Analyse.
+	cfg Config
+
+	// defaultVCS is the org-wide default, used for repositories that don't
+	// match an entry in vcsOverrides or the global vcsRegistry, itself
+	// defaulting to GitVCS. Callers wire this up from whatever holds
+	// org-wide VCS preferences, e.g. a row read from the database.
+	defaultVCS VCS
+
+	// vcsOverrides maps a repository URL to the VCS used for it, taking
+	// priority over defaultVCS and vcsRegistry. This is the per-repo
+	// override store: NewFileSystem seeds it from overrides (read from the
+	// database by the caller, keyed by repository URL), and SetVCSOverride
+	// updates it afterwards, e.g. when the caller re-polls the database.
+	vcsOverrides map[string]VCS
+
+	// modules configures modules mode, used instead of the legacy GOPATH
+	// layout whenever a repository has a go.mod at its root.
+	modules ModulesConfig
 }
 
 // Ensure FileSystem implements Analyser
 var _ Analyser = (*FileSystem)(nil)
 
-func NewFileSystem(gopath string) (*FileSystem, error) {
+// NewFileSystem returns a FileSystem that clones repositories using, in
+// priority order: the entry in overrides matching the repository URL (a
+// per-repo preference, keyed exactly as stored in the database), the VCS
+// registered for the URL's scheme/host (see RegisterVCS), then defaultVCS
+// (an org-wide preference, GitVCS if nil). overrides is copied, so the
+// caller's map may be reused or discarded; use SetVCSOverride to change an
+// entry afterwards, e.g. after re-reading the database. Workspaces are
+// pre-created below root, poolSize of them (at least 1; each Analyse call
+// blocks until one is free). Repositories with a go.mod at their root are
+// built in modules mode using modules, see ModulesConfig; a zero value
+// falls back to the legacy GOPATH layout for every repository.
+func NewFileSystem(root string, poolSize int, cfg Config, defaultVCS VCS, modules ModulesConfig, overrides map[string]VCS) (*FileSystem, error) {
+	if defaultVCS == nil {
+		defaultVCS = GitVCS{}
+	}
+
+	pool, err := newWorkspacePool(root, poolSize)
+	if err != nil {
+		return nil, err
+	}
+
 	fs := &FileSystem{
-		gopath: gopath,
+		pool:       pool,
+		cfg:        cfg,
+		defaultVCS: defaultVCS,
+		modules:    modules,
 	}
 
-	// TODO check if gopath exists, and directory structure exists mkdirs if not
-	// also check the ensure they are writable
-	// $GOPATH/{src,pkg,bin}, $GOPATH/src/gopherci/
+	for repoURL, vcs := range overrides {
+		fs.SetVCSOverride(repoURL, vcs)
+	}
 
 	return fs, nil
 }
 
+// SetVCSOverride makes fs use vcs for repoURL, taking priority over
+// fs.defaultVCS and any VCS registered globally via RegisterVCS. Use this to
+// apply a per-repo preference, e.g. one read from the database, either at
+// construction time via NewFileSystem's overrides or later as preferences
+// change.
+func (fs *FileSystem) SetVCSOverride(repoURL string, vcs VCS) {
+	if fs.vcsOverrides == nil {
+		fs.vcsOverrides = make(map[string]VCS)
+	}
+	fs.vcsOverrides[repoURL] = vcs
+}
+
+// resolveVCS returns the VCS to use for repoURL, in order of priority:
+// fs.vcsOverrides, the globally registered VCS for repoURL's scheme/host,
+// then fs.defaultVCS.
+func (fs *FileSystem) resolveVCS(repoURL string) VCS {
+	if vcs, ok := fs.vcsOverrides[repoURL]; ok {
+		return vcs
+	}
+	return VCSForURL(repoURL, fs.defaultVCS)
+}
+
 // Analyse implements Analyser interface
-func (fs *FileSystem) Analyse(repoURL, branch, diffURL string) ([]Issue, error) {
-	log.Printf("fs.Analyse repoURL %q branch %q diffURL %q GOPATH %q", repoURL, branch, diffURL, fs.gopath)
+func (fs *FileSystem) Analyse(ctx context.Context, repoURL, branch, diffURL string) ([]Issue, error) {
+	log.Printf("fs.Analyse repoURL %q branch %q diffURL %q", repoURL, branch, diffURL)
+
+	if fs.cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fs.cfg.TotalTimeout)
+		defer cancel()
+	}
 
 	// download patch
-	patch, err := http.Get(diffURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, diffURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer patch.Body.Close()
 
-	// make temp dir
-	tmpdir, err := fs.mktemp()
+	// lease a workspace, always releasing it for reuse, even if a clone
+	// failed partway through and left the checkout in a bad state: release
+	// scrubs it before the workspace returns to the pool.
+	gopath, release, err := fs.pool.lease(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	// TODO on second thought, I was using tmpdir to allow safe concurrency
-	// but go get isn't safe to run concurrently either. Perhaps it'll just be
-	// better to either limit concurrency with some semaphore or create entire
-	// gopaths separately.
+	// A reused workspace still holds the previous lease's scrubbed checkout,
+	// remove it so the clone below targets a directory that doesn't exist.
+	repoDir := filepath.Join(gopath, "src", "gopherci", repoDirName)
+	if err := os.RemoveAll(repoDir); err != nil {
+		return nil, errors.Wrap(err, "could not remove previous repository checkout")
+	}
+	if err := os.MkdirAll(filepath.Dir(repoDir), 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create repository checkout parent dir")
+	}
 
 	// clone repo
-	// TODO check out https://godoc.org/golang.org/x/tools/go/vcs to be agnostic
-	cmd := exec.Command("git", "clone", "--branch", branch, "--depth", "0", "--single-branch", repoURL, tmpdir)
-	log.Printf("path: %v %v, dir: %v, env: %v", cmd.Path, cmd.Args, cmd.Dir, cmd.Env)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("could not %v %v: %s\n%s", cmd.Path, cmd.Args, err, out)
+	cloneCtx, cancel := withPhaseTimeout(ctx, fs.cfg.CloneTimeout)
+	defer cancel()
+
+	vcs := fs.resolveVCS(repoURL)
+	if err := vcs.Clone(cloneCtx, repoURL, branch, repoDir); err != nil {
+		return nil, errors.Wrap(err, "could not clone repository")
 	}
-	//defer os.RemoveAll(tmpdir)
 
-	log.Println("clone success to:", tmpdir)
+	log.Println("clone success to:", repoDir)
 
 	// fetch dependencies, some static analysis tools require building a project
 
@@ -89,9 +175,26 @@ func (fs *FileSystem) Analyse(repoURL, branch, diffURL string) ([]Issue, error)
 
 	// run go vet
 	// TODO expand this to have a user configurable amount of tools/libraries
-	cmd = exec.Command("go", "vet", "./...")
-	cmd.Env = []string{"GOPATH=" + fs.gopath}
-	cmd.Dir = tmpdir
+	toolCtx, cancel := withPhaseTimeout(ctx, fs.cfg.ToolTimeout)
+	defer cancel()
+
+	env := []string{"GOPATH=" + gopath}
+	if hasGoMod(repoDir) && fs.modules.ModCache != "" {
+		log.Println("go.mod found, building in modules mode using", fs.modules.ModCache)
+		env = fs.modules.env(repoDir)
+
+		// Only the download step writes to ModCache, so only it needs to
+		// exclude other analyses; GOFLAGS=-mod=readonly means the go vet
+		// below won't itself write to the cache, so it can run unlocked and
+		// concurrently with other analyses sharing ModCache.
+		if err := fs.modules.populateModCache(toolCtx, repoDir, env); err != nil {
+			return nil, errors.Wrap(err, "could not populate module cache")
+		}
+	}
+
+	cmd := exec.CommandContext(toolCtx, "go", "vet", "./...")
+	cmd.Env = env
+	cmd.Dir = repoDir
 	cmd.Stderr = &allIssues
 	cmd.Stdout = &allIssues
 	log.Printf("path: %v %v, dir: %v, env: %v", cmd.Path, cmd.Args, cmd.Dir, cmd.Env)
@@ -101,36 +204,25 @@ func (fs *FileSystem) Analyse(repoURL, branch, diffURL string) ([]Issue, error)
 	_ = cmd.Run()
 	log.Println("go vet output:", allIssues.String())
 
-	checker := revgrep.Checker{
-		Patch: patch.Body,
-		Debug: os.Stdout,
-	}
-
-	revIssues, err := checker.Check(&allIssues, ioutil.Discard)
+	parsed, err := parseToolOutput(FormatText, "go vet", allIssues.Bytes())
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("revgrep found %v issues", len(revIssues))
 
-	var issues []Issue
-	for _, issue := range revIssues {
-		issues = append(issues, Issue{
-			File:    issue.File,
-			HunkPos: issue.HunkPos,
-			Issue:   issue.Issue,
-		})
+	issues, err := filterIssuesByPatch(parsed, patch.Body)
+	if err != nil {
+		return nil, err
 	}
+	log.Printf("found %v issues within the patch", len(issues))
 
 	return issues, nil
 }
 
-// mktemp makes a random and temporary directory within GOPATH/src/gopherci
-func (fs *FileSystem) mktemp() (string, error) {
-	rand := strconv.Itoa(int(time.Now().UnixNano()))
-	dir := filepath.Join(fs.gopath, "src", "gopherci", rand)
-	log.Println("mktemp:", dir)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", errors.Wrap(err, "mktemp cannot mkdir")
+// withPhaseTimeout returns a child of ctx bounded additionally by d, unless d
+// is zero in which case ctx is returned unmodified along with a no-op cancel.
+func withPhaseTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
 	}
-	return dir, nil
+	return context.WithTimeout(ctx, d)
 }
@@ -0,0 +1,42 @@
+package analyser
+
+import "fmt"
+
+// ToolFormat identifies the structure of a tool's output, used to select the
+// parser that turns it into Issues. Previously all tool output was fed
+// directly to revgrep.Checker, which only understands "file:line: message"
+// text and drops everything else a tool might report (column, severity,
+// rule id, suggested fix).
+type ToolFormat string
+
+const (
+	// FormatText is plain "file:line: message" output, e.g. go vet. This is
+	// the default when a Tool doesn't specify a Format.
+	FormatText ToolFormat = "text"
+	// FormatCheckstyle is XML in the Checkstyle format emitted by many Java
+	// tools and supported as an output option by several Go linters.
+	FormatCheckstyle ToolFormat = "checkstyle"
+	// FormatSARIF is the Static Analysis Results Interchange Format, JSON.
+	FormatSARIF ToolFormat = "sarif"
+	// FormatGolangciJSON is golangci-lint's `--out-format json` output.
+	FormatGolangciJSON ToolFormat = "json-golangci"
+)
+
+// parseToolOutput parses raw, the output of toolName in the given format,
+// into Issues. The returned Issues have File, Line and Issue (at minimum)
+// populated but are not yet filtered to the lines changed in a patch, see
+// filterIssuesByPatch.
+func parseToolOutput(format ToolFormat, toolName string, raw []byte) ([]Issue, error) {
+	switch format {
+	case FormatCheckstyle:
+		return parseCheckstyle(toolName, raw)
+	case FormatSARIF:
+		return parseSARIF(toolName, raw)
+	case FormatGolangciJSON:
+		return parseGolangciJSON(toolName, raw)
+	case FormatText, "":
+		return parseText(toolName, raw)
+	default:
+		return nil, fmt.Errorf("unknown tool format %q", format)
+	}
+}
@@ -0,0 +1,48 @@
+package analyser
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// checkstyleReport mirrors the subset of the Checkstyle XML schema that
+// GopherCI cares about, as emitted by e.g. golangci-lint
+// --out-format=checkstyle.
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Column   int    `xml:"column,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+// parseCheckstyle parses a Checkstyle XML report.
+func parseCheckstyle(toolName string, raw []byte) ([]Issue, error) {
+	var report checkstyleReport
+	if err := xml.Unmarshal(raw, &report); err != nil {
+		return nil, errors.Wrap(err, "could not parse checkstyle output")
+	}
+
+	var issues []Issue
+	for _, file := range report.Files {
+		for _, e := range file.Errors {
+			issues = append(issues, Issue{
+				File:     file.Name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Severity: e.Severity,
+				Rule:     e.Source,
+				Issue:    fmt.Sprintf("%v: %v", toolName, e.Message),
+			})
+		}
+	}
+
+	return issues, nil
+}
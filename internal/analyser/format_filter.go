@@ -0,0 +1,54 @@
+package analyser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/bradleyfalzon/revgrep"
+)
+
+// filterIssuesByPatch restricts issues to those on a line changed by patch,
+// setting HunkPos on each surviving Issue to its position within the diff
+// hunk. All other fields (Severity, Rule, SuggestedFix, etc) are preserved
+// from the parsed Issue, which revgrep itself knows nothing about.
+//
+// It works by feeding revgrep.Checker a synthetic "file:line: index" report,
+// one line per issue, then mapping the surviving lines back to the original
+// Issues by index. This lets structured issues (SARIF, checkstyle, etc)
+// benefit from revgrep's hunk-position logic without it needing to
+// understand any format beyond plain text.
+func filterIssuesByPatch(issues []Issue, patch io.Reader) ([]Issue, error) {
+	var synthetic bytes.Buffer
+	for i, issue := range issues {
+		fmt.Fprintf(&synthetic, "%v:%v: %v\n", issue.File, issue.Line, i)
+	}
+
+	checker := revgrep.Checker{Patch: patch}
+
+	revIssues, err := checker.Check(&synthetic, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Issue
+	for _, revIssue := range revIssues {
+		// revIssue.Issue is the synthetic line as a whole ("file:line: N");
+		// Message is what revgrep parsed out after the file:line prefix,
+		// which is our index N.
+		i, err := strconv.Atoi(revIssue.Message)
+		if err != nil || i < 0 || i >= len(issues) {
+			// Should never happen, the synthetic report above is entirely
+			// under our control.
+			continue
+		}
+
+		issue := issues[i]
+		issue.HunkPos = revIssue.HunkPos
+		filtered = append(filtered, issue)
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,35 @@
+package analyser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterIssuesByPatch(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+index 0000000..6362395 100644
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+-var _ = fmt.Sprintln()
++var _ = fmt.Sprintln() // changed`
+
+	issues := []Issue{
+		{File: "main.go", Line: 1, Issue: "tool: changed line"},
+		{File: "main.go", Line: 99, Issue: "tool: untouched line"},
+		{File: "other.go", Line: 1, Issue: "tool: different file"},
+	}
+
+	filtered, err := filterIssuesByPatch(issues, strings.NewReader(patch))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []Issue{
+		{File: "main.go", Line: 1, HunkPos: 2, Issue: "tool: changed line"},
+	}
+	if !reflect.DeepEqual(expected, filtered) {
+		t.Errorf("\nhave %+v\nwant %+v", filtered, expected)
+	}
+}
@@ -0,0 +1,56 @@
+package analyser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// golangciReport mirrors the subset of golangci-lint's `--out-format json`
+// output that GopherCI cares about.
+type golangciReport struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+			Column   int    `json:"Column"`
+		} `json:"Pos"`
+		Replacement *struct {
+			NewLines []string `json:"NewLines"`
+		} `json:"Replacement"`
+	} `json:"Issues"`
+}
+
+// parseGolangciJSON parses golangci-lint's JSON output. toolName is ignored
+// in favour of each issue's own FromLinter, since golangci-lint aggregates
+// many linters into a single run.
+func parseGolangciJSON(toolName string, raw []byte) ([]Issue, error) {
+	var report golangciReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, errors.Wrap(err, "could not parse golangci-lint output")
+	}
+
+	var issues []Issue
+	for _, i := range report.Issues {
+		issue := Issue{
+			File:     i.Pos.Filename,
+			Line:     i.Pos.Line,
+			Column:   i.Pos.Column,
+			Severity: i.Severity,
+			Rule:     i.FromLinter,
+			Issue:    fmt.Sprintf("%v: %v", i.FromLinter, i.Text),
+		}
+
+		if i.Replacement != nil && len(i.Replacement.NewLines) > 0 {
+			issue.SuggestedFix = i.Replacement.NewLines[0]
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
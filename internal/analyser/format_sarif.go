@@ -0,0 +1,80 @@
+package analyser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// sarifLog mirrors the subset of the SARIF v2.1.0 schema that GopherCI cares
+// about. See https://docs.oasis-open.org/sarif/sarif/v2.1.0/ for the full
+// format.
+type sarifLog struct {
+	Runs []struct {
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine   int `json:"startLine"`
+						StartColumn int `json:"startColumn"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+			Fixes []struct {
+				ArtifactChanges []struct {
+					Replacements []struct {
+						InsertedContent struct {
+							Text string `json:"text"`
+						} `json:"insertedContent"`
+					} `json:"replacements"`
+				} `json:"artifactChanges"`
+			} `json:"fixes"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// parseSARIF parses a SARIF log.
+func parseSARIF(toolName string, raw []byte) ([]Issue, error) {
+	var log sarifLog
+	if err := json.Unmarshal(raw, &log); err != nil {
+		return nil, errors.Wrap(err, "could not parse sarif output")
+	}
+
+	var issues []Issue
+	for _, run := range log.Runs {
+		for _, result := range run.Results {
+			issue := Issue{
+				Severity: result.Level,
+				Rule:     result.RuleID,
+				Issue:    fmt.Sprintf("%v: %v", toolName, result.Message.Text),
+			}
+
+			if len(result.Locations) > 0 {
+				loc := result.Locations[0].PhysicalLocation
+				issue.File = loc.ArtifactLocation.URI
+				issue.Line = loc.Region.StartLine
+				issue.Column = loc.Region.StartColumn
+			}
+
+			if len(result.Fixes) > 0 && len(result.Fixes[0].ArtifactChanges) > 0 {
+				changes := result.Fixes[0].ArtifactChanges[0].Replacements
+				if len(changes) > 0 {
+					issue.SuggestedFix = changes[0].InsertedContent.Text
+				}
+			}
+
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
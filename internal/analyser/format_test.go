@@ -0,0 +1,95 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	raw := []byte("main.go:1: error1\nnot a match\nsubdir/helper.go:4: undefined: bar\nmain.go:10:5: error with column\n")
+
+	issues, err := parseText("vet", raw)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []Issue{
+		{File: "main.go", Line: 1, Issue: "vet: error1"},
+		{File: "subdir/helper.go", Line: 4, Issue: "vet: undefined: bar"},
+		{File: "main.go", Line: 10, Column: 5, Issue: "vet: error with column"},
+	}
+	if !reflect.DeepEqual(expected, issues) {
+		t.Errorf("\nhave %+v\nwant %+v", issues, expected)
+	}
+}
+
+func TestParseCheckstyle(t *testing.T) {
+	raw := readTestdata(t, "checkstyle.xml")
+
+	issues, err := parseCheckstyle("tool", raw)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []Issue{
+		{File: "main.go", Line: 12, Column: 2, Severity: "warning", Rule: "golint", Issue: "tool: exported function Foo should have comment"},
+		{File: "subdir/helper.go", Line: 4, Column: 1, Severity: "error", Rule: "vet", Issue: "tool: undefined: bar"},
+	}
+	if !reflect.DeepEqual(expected, issues) {
+		t.Errorf("\nhave %+v\nwant %+v", issues, expected)
+	}
+}
+
+func TestParseSARIF(t *testing.T) {
+	raw := readTestdata(t, "sarif.json")
+
+	issues, err := parseSARIF("staticcheck", raw)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []Issue{
+		{
+			File: "main.go", Line: 20, Column: 2, Severity: "error", Rule: "SA4006",
+			Issue:        "staticcheck: this value of x is never used",
+			SuggestedFix: "_ = x",
+		},
+	}
+	if !reflect.DeepEqual(expected, issues) {
+		t.Errorf("\nhave %+v\nwant %+v", issues, expected)
+	}
+}
+
+func TestParseGolangciJSON(t *testing.T) {
+	raw := readTestdata(t, "golangci.json")
+
+	issues, err := parseGolangciJSON("golangci-lint", raw)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []Issue{
+		{
+			File: "main.go", Line: 20, Column: 2, Severity: "error", Rule: "staticcheck",
+			Issue:        "staticcheck: this value of x is never used (SA4006)",
+			SuggestedFix: "\t_ = x",
+		},
+		{
+			File: "main.go", Line: 12, Column: 1, Severity: "warning", Rule: "golint",
+			Issue: "golint: exported function Foo should have comment",
+		},
+	}
+	if !reflect.DeepEqual(expected, issues) {
+		t.Errorf("\nhave %+v\nwant %+v", issues, expected)
+	}
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal("could not read testdata:", err)
+	}
+	return raw
+}
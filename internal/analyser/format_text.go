@@ -0,0 +1,47 @@
+package analyser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// textLineRE matches "file:line:col: message" output, e.g. from go vet,
+// with col optional, mirroring the format revgrep itself parses.
+var textLineRE = regexp.MustCompile(`^(.*?):([0-9]+):([0-9]+)?:?\s*(.*)$`)
+
+// parseText parses "file:line[:col]: message" output, e.g. from go vet,
+// prefixing each message with toolName so issues from different tools
+// remain distinguishable once merged.
+func parseText(toolName string, raw []byte) ([]Issue, error) {
+	var issues []Issue
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		m := textLineRE.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+
+		var col int
+		if m[3] != "" {
+			col, _ = strconv.Atoi(m[3])
+		}
+
+		issues = append(issues, Issue{
+			File:   m[1],
+			Line:   line,
+			Column: col,
+			Issue:  fmt.Sprintf("%v: %v", toolName, m[4]),
+		})
+	}
+
+	return issues, scanner.Err()
+}
@@ -0,0 +1,113 @@
+package analyser
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ModulesConfig configures Go modules mode, used instead of the legacy
+// $GOPATH/src/gopherci layout whenever the repository being analysed has a
+// go.mod at its root.
+type ModulesConfig struct {
+	// ModCache is a directory shared across repositories and analyses that
+	// downloaded modules are cached in (GOPATH/pkg/mod below it). Analysing
+	// the same dependency twice, even for different repositories, reuses
+	// this cache instead of re-downloading. Required for modules mode; if
+	// empty, FileSystem falls back to the legacy GOPATH layout even for
+	// repositories with a go.mod.
+	ModCache string
+	// Proxy sets GOPROXY, e.g. to point at an internal Athens instance.
+	// Defaults to the go command's own default if empty.
+	Proxy string
+	// SumDB sets GOSUMDB, "off" disables checksum database verification
+	// entirely, useful when Proxy doesn't mirror sum.golang.org.
+	SumDB string
+	// Private sets GOPRIVATE, a comma separated list of module path glob
+	// patterns that should bypass Proxy and SumDB, e.g.
+	// "github.com/myorg/*".
+	Private string
+}
+
+// hasGoMod reports whether dir has a go.mod file at its root.
+func hasGoMod(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
+
+// env returns the environment FileSystem should run go (and static analysis
+// tools that shell out to it) with for a checkout at dir, in modules mode.
+func (m ModulesConfig) env(dir string) []string {
+	env := append(os.Environ(),
+		"GO111MODULE=on",
+		"GOFLAGS=-mod=readonly",
+		"GOPATH="+m.ModCache,
+	)
+	if m.Proxy != "" {
+		env = append(env, "GOPROXY="+m.Proxy)
+	}
+	if m.SumDB != "" {
+		env = append(env, "GOSUMDB="+m.SumDB)
+	}
+	if m.Private != "" {
+		env = append(env, "GOPRIVATE="+m.Private)
+	}
+	return env
+}
+
+// populateModCache downloads dir's module dependencies into m.ModCache,
+// holding an exclusive lock for the duration so concurrent Analyse calls
+// (potentially from separate processes) sharing m.ModCache don't race on
+// writing to it, mirroring the locking cmd/go itself applies around its
+// module cache.
+//
+// Once this returns, m.ModCache is populated and the lock is released:
+// subsequent commands run with env (GOFLAGS=-mod=readonly) only read from
+// the cache, so they don't need to hold the lock and can run concurrently
+// with other analyses, unlike locking around the whole analysis.
+func (m ModulesConfig) populateModCache(ctx context.Context, dir string, env []string) error {
+	unlock, err := m.lockModCache()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cmd := exec.CommandContext(ctx, "go", "mod", "download")
+	cmd.Dir = dir
+	cmd.Env = env
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "go mod download failed: %s", out)
+	}
+	return nil
+}
+
+// lockModCache excludes other callers from writing to ModCache while
+// unlock hasn't been called, similar to the locking cmd/go itself applies
+// around its module cache. Callers should hold it only around the actual
+// writes, e.g. populateModCache, not around reads.
+func (m ModulesConfig) lockModCache() (unlock func() error, err error) {
+	if err := os.MkdirAll(m.ModCache, 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create module cache dir")
+	}
+
+	f, err := os.OpenFile(filepath.Join(m.ModCache, ".gopherci-lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open module cache lock file")
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "could not lock module cache")
+	}
+
+	return func() error {
+		defer f.Close()
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,93 @@
+//go:build integration
+// +build integration
+
+package analyser
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestFileSystem_Analyse_modulesCacheReused analyses a local, dependency-free
+// module twice through a single-workspace pool sharing one ModCache, and
+// asserts both analyses succeed and that GOMODCACHE (via GOPATH/pkg/mod
+// here) exists afterwards. Requires git and go on PATH, run with
+// `go test -tags integration ./...`.
+func TestFileSystem_Analyse_modulesCacheReused(t *testing.T) {
+	remote := newLocalGitModule(t)
+
+	diffServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("diff --git a/main.go b/main.go\n"))
+	}))
+	defer diffServer.Close()
+
+	modCache, err := ioutil.TempDir("", "gopherci-modcache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(modCache)
+
+	poolRoot, err := ioutil.TempDir("", "gopherci-pool-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(poolRoot)
+
+	fs, err := NewFileSystem(poolRoot, 1, Config{}, GitVCS{}, ModulesConfig{ModCache: modCache}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := fs.Analyse(context.Background(), remote, "master", diffServer.URL); err != nil {
+			t.Fatalf("analysis %v failed: %v", i, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(modCache, "pkg", "mod")); err != nil {
+		t.Errorf("expected module cache to be populated under GOPATH/pkg/mod: %v", err)
+	}
+}
+
+// newLocalGitModule creates a temporary, single-commit git repository
+// containing a trivial, dependency-free Go module, and returns a file://
+// URL it can be cloned from.
+func newLocalGitModule(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gopherci-remote-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"go.mod":  "module example.com/gopherci-test\n\ngo 1.16\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "-b", "master"},
+		{"add", "."},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	return "file://" + dir
+}
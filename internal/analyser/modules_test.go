@@ -0,0 +1,63 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHasGoMod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherci-hasgomod-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if hasGoMod(dir) {
+		t.Error("expected no go.mod in empty dir")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasGoMod(dir) {
+		t.Error("expected go.mod to be found")
+	}
+}
+
+func TestModulesConfig_env(t *testing.T) {
+	m := ModulesConfig{
+		ModCache: "/tmp/gopherci-modcache",
+		Proxy:    "https://proxy.example.com",
+		SumDB:    "off",
+		Private:  "github.com/myorg/*",
+	}
+
+	env := m.env("/tmp/checkout")
+
+	want := map[string]string{
+		"GO111MODULE": "on",
+		"GOFLAGS":     "-mod=readonly",
+		"GOPATH":      "/tmp/gopherci-modcache",
+		"GOPROXY":     "https://proxy.example.com",
+		"GOSUMDB":     "off",
+		"GOPRIVATE":   "github.com/myorg/*",
+	}
+
+	for k, v := range want {
+		if !containsEnv(env, k+"="+v) {
+			t.Errorf("expected env to contain %v=%v, got %v", k, v, env)
+		}
+	}
+}
+
+func containsEnv(env []string, kv string) bool {
+	for _, e := range env {
+		if e == kv {
+			return true
+		}
+	}
+	return false
+}
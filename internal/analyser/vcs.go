@@ -0,0 +1,79 @@
+package analyser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+)
+
+// VCS abstracts the version control operations required to analyse a
+// repository, allowing FileSystem to support version control systems other
+// than git without changing its analysis logic. Container does not use VCS:
+// it always clones with git inside its container.
+//
+// See https://godoc.org/golang.org/x/tools/go/vcs for the equivalent
+// abstraction used by the go tool itself; XToolsVCS adapts it to VCS for
+// systems it supports.
+type VCS interface {
+	// Clone checks out ref from url into dir, which must not already exist.
+	Clone(ctx context.Context, url, ref, dir string) error
+}
+
+// vcsRegistry maps a URL scheme or host to the VCS implementation used to
+// operate on repositories with that scheme/host, populated by RegisterVCS.
+var vcsRegistry = map[string]VCS{}
+
+// RegisterVCS registers vcs as the implementation used for repository URLs
+// whose scheme or host matches key, e.g. "hg", "bzr", "fossil", or a
+// specific host such as "bitbucket.org". Registering the same key twice
+// replaces the previous registration.
+func RegisterVCS(key string, vcs VCS) {
+	vcsRegistry[key] = vcs
+}
+
+// VCSForURL returns the VCS registered for repoURL, preferring a match on
+// host over scheme, falling back to fallback if neither is registered or
+// repoURL cannot be parsed.
+func VCSForURL(repoURL string, fallback VCS) VCS {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return fallback
+	}
+	if vcs, ok := vcsRegistry[u.Host]; ok {
+		return vcs
+	}
+	if vcs, ok := vcsRegistry[u.Scheme]; ok {
+		return vcs
+	}
+	return fallback
+}
+
+func init() {
+	RegisterVCS("git", GitVCS{})
+}
+
+// GitVCS implements VCS using the git binary directly. It's registered as
+// the default for the "git" scheme, and is also FileSystem's fallback VCS
+// when no repo/org specific override applies.
+type GitVCS struct{}
+
+var _ VCS = GitVCS{}
+
+// Clone implements VCS.
+func (GitVCS) Clone(ctx context.Context, url, ref, dir string) error {
+	return runVCSCmd(ctx, "", "git", "clone", "--branch", ref, "--depth", "1", "--single-branch", url, dir)
+}
+
+// runVCSCmd runs name with args in dir (the current directory if dir is
+// empty) and returns its combined output as an error if it fails.
+func runVCSCmd(ctx context.Context, dir, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not %v %v: %s\n%s", cmd.Path, cmd.Args, err, out)
+	}
+	return nil
+}
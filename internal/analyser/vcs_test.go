@@ -0,0 +1,30 @@
+package analyser
+
+import "testing"
+
+func TestVCSForURL(t *testing.T) {
+	mercurial := XToolsVCS{}
+	RegisterVCS("mercurial.example.com", mercurial)
+	defer delete(vcsRegistry, "mercurial.example.com")
+
+	fallback := GitVCS{}
+
+	tests := []struct {
+		name    string
+		repoURL string
+		want    VCS
+	}{
+		{"registered host", "https://mercurial.example.com/foo/bar", mercurial},
+		{"registered scheme", "git://github.com/foo/bar", GitVCS{}},
+		{"unregistered falls back", "https://unknown.example.com/foo/bar", fallback},
+		{"unparsable falls back", "://not-a-url", fallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VCSForURL(tt.repoURL, fallback); got != tt.want {
+				t.Errorf("VCSForURL(%q) = %#v, want %#v", tt.repoURL, got, tt.want)
+			}
+		})
+	}
+}
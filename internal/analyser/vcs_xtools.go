@@ -0,0 +1,37 @@
+package analyser
+
+import (
+	"context"
+
+	xvcs "golang.org/x/tools/go/vcs"
+)
+
+// XToolsVCS adapts a golang.org/x/tools/go/vcs.Cmd to the VCS interface,
+// giving GopherCI basic support for the version control systems the go tool
+// itself understands (Mercurial, Bazaar, Subversion) beyond git, which is
+// handled natively by GitVCS. x/tools/go/vcs has no Fossil support, so
+// that's not registered below despite what earlier revisions of this
+// comment claimed.
+//
+// x/tools/go/vcs predates context.Context, so ctx is only honoured before
+// the underlying command starts, not within it; a slow hg/bzr/svn process
+// cannot currently be killed mid-command.
+type XToolsVCS struct {
+	Cmd *xvcs.Cmd
+}
+
+var _ VCS = XToolsVCS{}
+
+func init() {
+	RegisterVCS("hg", XToolsVCS{Cmd: xvcs.ByCmd("hg")})
+	RegisterVCS("bzr", XToolsVCS{Cmd: xvcs.ByCmd("bzr")})
+	RegisterVCS("svn", XToolsVCS{Cmd: xvcs.ByCmd("svn")})
+}
+
+// Clone implements VCS.
+func (x XToolsVCS) Clone(ctx context.Context, url, ref, dir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return x.Cmd.CreateAtRev(dir, url, ref)
+}
@@ -0,0 +1,104 @@
+package analyser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// repoDirName is the fixed directory a workspace's repository checkout
+// lives in, within that workspace's GOPATH.
+const repoDirName = "repo"
+
+// workspacePool hands out a bounded number of reusable GOPATH workspaces,
+// each rooted at $root/workspace-N, guarded by a weighted semaphore so at
+// most size workspaces are leased concurrently.
+//
+// A workspace is scrubbed back to a clean checkout when released, rather
+// than being deleted and recreated on every Analyse call: this is both
+// cheaper (no repeated $GOPATH/{src,pkg,bin} setup) and removes the race the
+// old timestamp-based mktemp had, where two Analyse calls landing in the
+// same nanosecond would collide on the same directory name.
+type workspacePool struct {
+	root string
+	sem  *semaphore.Weighted
+	free chan string // paths to pre-created workspace dirs, buffered to size
+}
+
+// newWorkspacePool pre-creates size workspace directories under root. A size
+// below 1 is treated as 1.
+func newWorkspacePool(root string, size int) (*workspacePool, error) {
+	if size <= 0 {
+		size = 1
+	}
+
+	p := &workspacePool{
+		root: root,
+		sem:  semaphore.NewWeighted(int64(size)),
+		free: make(chan string, size),
+	}
+
+	for i := 0; i < size; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("workspace-%d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrapf(err, "could not create workspace %v", dir)
+		}
+		p.free <- dir
+	}
+
+	return p, nil
+}
+
+// lease blocks until a workspace is available or ctx is done, returning the
+// workspace's GOPATH and a release func. release must always be called
+// exactly once, regardless of whether Analyse succeeded, so the workspace
+// is scrubbed and returned to the pool instead of leaking.
+func (p *workspacePool) lease(ctx context.Context) (gopath string, release func(), err error) {
+	if err := p.sem.Acquire(ctx, 1); err != nil {
+		return "", nil, err
+	}
+
+	dir := <-p.free
+
+	release = func() {
+		scrubWorkspace(dir)
+		p.free <- dir
+		p.sem.Release(1)
+	}
+
+	return dir, release, nil
+}
+
+// scrubWorkspace resets gopath's checked-out repository back to a clean
+// state for reuse by the next lease, it never removes gopath itself.
+func scrubWorkspace(gopath string) {
+	repoDir := filepath.Join(gopath, "src", "gopherci", repoDirName)
+
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); err != nil {
+		// No existing checkout (first use, or a clone that never
+		// completed), just ensure nothing else is left behind.
+		os.RemoveAll(repoDir)
+		return
+	}
+
+	// Fast path: scrub the existing checkout in place rather than deleting
+	// and recreating it.
+	for _, args := range [][]string{
+		{"clean", "-fdx"},
+		{"reset", "--hard"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			log.Printf("workspace scrub: git %v failed, falling back to full wipe: %v\n%s", args, err, out)
+			os.RemoveAll(repoDir)
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package analyser
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkspacePool_leaseBounded(t *testing.T) {
+	root, err := ioutil.TempDir("", "gopherci-pool-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	pool, err := newWorkspacePool(root, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gopath1, release1, err := pool.lease(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := pool.lease(ctx); err == nil {
+		t.Error("expected second lease to block until the first is released")
+	}
+
+	release1()
+
+	gopath2, release2, err := pool.lease(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release2()
+
+	if gopath1 != gopath2 {
+		t.Errorf("expected the released workspace to be reused, got %v then %v", gopath1, gopath2)
+	}
+}
+
+func TestScrubWorkspace(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gopherci-workspace-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+
+	repoDir := filepath.Join(gopath, "src", "gopherci", repoDirName)
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// No .git directory: scrub should simply remove the leftover dir.
+	if err := ioutil.WriteFile(filepath.Join(repoDir, "leftover.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scrubWorkspace(gopath)
+
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		t.Errorf("expected repoDir to be removed when there's no git checkout, got err=%v", err)
+	}
+}